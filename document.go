@@ -0,0 +1,454 @@
+package ofx
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+// InvestmentTransactionType indicates the kind of activity recorded in an
+// investment transaction list (e.g. BUYSTOCK, SELLSTOCK, INCOME).
+type InvestmentTransactionType int
+
+const (
+	INVUNKNOWN InvestmentTransactionType = iota
+	BUYSTOCK
+	SELLSTOCK
+	INCOME
+)
+
+// BankAccount identifies the account a bank statement (STMTRS) belongs to.
+type BankAccount struct {
+	Type          AccountType
+	BankCode      string
+	BranchCode    string
+	AccountNumber string
+}
+
+// BankStatement is a single STMTRS: a bank account plus its transactions.
+type BankStatement struct {
+	Account      BankAccount
+	Transactions []*Transaction
+}
+
+// CreditCardAccount identifies the account a credit card statement
+// (CCSTMTRS, via CCACCTFROM) belongs to.
+type CreditCardAccount struct {
+	AccountNumber string
+}
+
+// CreditCardStatement is a single CCSTMTRS: a credit card account plus its
+// transactions.
+type CreditCardStatement struct {
+	Account      CreditCardAccount
+	Transactions []*Transaction
+}
+
+// InvestmentAccount identifies the account an investment statement
+// (INVSTMTRS, via INVACCTFROM) belongs to.
+type InvestmentAccount struct {
+	BrokerID      string
+	AccountNumber string
+}
+
+// InvestmentTransaction is a single entry from an INVTRANLIST, such as
+// BUYSTOCK, SELLSTOCK, or INCOME.
+type InvestmentTransaction struct {
+	Type       InvestmentTransactionType
+	ID         string
+	SecurityID string
+	PostedDate time.Time
+	Units      Amount
+	UnitPrice  Amount
+	Total      Amount
+	Memo       string
+}
+
+// Position is a single holding under INVPOSLIST.
+type Position struct {
+	SecurityID  string
+	Units       Amount
+	MarketValue Amount
+}
+
+// InvestmentBalance is the INVBAL block of an investment statement.
+type InvestmentBalance struct {
+	AvailableCash Amount
+	MarginBalance Amount
+}
+
+// InvestmentStatement is a single INVSTMTRS: an investment account plus its
+// transactions, positions, and balance.
+type InvestmentStatement struct {
+	Account      InvestmentAccount
+	Transactions []*InvestmentTransaction
+	Positions    []*Position
+	Balance      *InvestmentBalance
+}
+
+// Document represents a parsed OFX document that may contain any mix of
+// bank, credit card, and investment statement responses.
+type Document struct {
+	Header               *Header
+	BankStatements       []*BankStatement
+	CreditCardStatements []*CreditCardStatement
+	InvestmentStatements []*InvestmentStatement
+}
+
+type docNextKey int
+
+const (
+	docNone docNextKey = iota
+	docAcctID
+	docBranchID
+	docBankID
+	docCCAcctID
+	docInvBrokerID
+	docInvAcctID
+	docTransAmount
+	docTransDatePosted
+	docTransID
+	docTransDesc
+	docTransMemo
+	docInvTransID
+	docInvSecID
+	docInvUnits
+	docInvUnitPrice
+	docInvTotal
+	docPosSecID
+	docPosUnits
+	docPosMktVal
+	docBalAvailCash
+	docBalMarginBalance
+)
+
+// ParseDocument parses an input stream into a Document, preserving every
+// statement response (bank, credit card, investment) found in it. Use Parse
+// instead if only the single-bank-statement case is needed.
+//
+// The header block is parsed first to determine whether the body is OFX 1.x
+// SGML or OFX 2.x XML; see Header for details.
+func ParseDocument(f io.Reader) (*Document, error) {
+	data, err := io.ReadAll(f)
+	if err != nil {
+		return nil, err
+	}
+
+	header, body := parseHeader(data)
+
+	var dec tokenDecoder
+	if header.isXML() {
+		dec = xml.NewDecoder(bytes.NewReader(body))
+	} else {
+		dec = newSGMLDecoder(body)
+	}
+
+	doc, err := decodeDocument(dec)
+	if err != nil {
+		return nil, err
+	}
+	doc.Header = header
+
+	return doc, nil
+}
+
+// decodeDocument drives the shared stack-based decode loop against any
+// tokenDecoder, whether it is backed by encoding/xml (OFX 2.x) or the SGML
+// scanner (OFX 1.x).
+func decodeDocument(dec tokenDecoder) (*Document, error) {
+	doc := &Document{}
+	var stack []string
+
+	next := docNone
+	var bankStmt *BankStatement = nil
+	var ccStmt *CreditCardStatement = nil
+	var invStmt *InvestmentStatement = nil
+	var trans *Transaction = nil
+	var invTrans *InvestmentTransaction = nil
+	var pos *Position = nil
+
+	for {
+		tok, err := dec.RawToken()
+		if err == io.EOF {
+			return doc, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("ofx: malformed document: %w", err)
+		}
+
+		switch t := tok.(type) {
+		case xml.StartElement:
+			stack = append(stack, t.Name.Local)
+
+			switch t.Name.Local {
+			case "STMTRS":
+				bankStmt = &BankStatement{Transactions: []*Transaction{}}
+
+			case "CCSTMTRS":
+				ccStmt = &CreditCardStatement{Transactions: []*Transaction{}}
+
+			case "INVSTMTRS":
+				invStmt = &InvestmentStatement{Transactions: []*InvestmentTransaction{}, Positions: []*Position{}}
+
+			case "ACCTID":
+				if ccStmt != nil && inStack(stack, "CCACCTFROM") {
+					next = docCCAcctID
+				} else if invStmt != nil && inStack(stack, "INVACCTFROM") {
+					next = docInvAcctID
+				} else {
+					next = docAcctID
+				}
+
+			case "BROKERID":
+				next = docInvBrokerID
+
+			case "BRANCHID":
+				next = docBranchID
+
+			case "BANKID":
+				next = docBankID
+
+			case "STMTTRN":
+				trans = &Transaction{}
+
+			case "BUYSTOCK", "SELLSTOCK", "INCOME":
+				invTrans = &InvestmentTransaction{Type: investmentTransactionType(t.Name.Local)}
+
+			case "POS", "POSSTOCK", "POSMF":
+				pos = &Position{}
+
+			case "FITID":
+				if invTrans != nil {
+					next = docInvTransID
+				} else {
+					next = docTransID
+				}
+
+			case "SECID", "UNIQUEID":
+				if pos != nil {
+					next = docPosSecID
+				} else if invTrans != nil {
+					next = docInvSecID
+				}
+
+			case "DTPOSTED":
+				next = docTransDatePosted
+
+			case "TRNAMT":
+				next = docTransAmount
+
+			case "UNITS":
+				if pos != nil {
+					next = docPosUnits
+				} else if invTrans != nil {
+					next = docInvUnits
+				}
+
+			case "UNITPRICE":
+				next = docInvUnitPrice
+
+			case "MKTVAL":
+				next = docPosMktVal
+
+			case "TOTAL":
+				next = docInvTotal
+
+			case "AVAILCASH":
+				next = docBalAvailCash
+
+			case "MARGINBALANCE":
+				next = docBalMarginBalance
+
+			case "NAME":
+				next = docTransDesc
+
+			case "MEMO":
+				next = docTransMemo
+			}
+
+		case xml.CharData:
+			res := strings.TrimSpace(string(t))
+
+			switch next {
+			case docAcctID:
+				bankStmt.Account.AccountNumber = res
+
+			case docCCAcctID:
+				ccStmt.Account.AccountNumber = res
+
+			case docInvAcctID:
+				invStmt.Account.AccountNumber = res
+
+			case docInvBrokerID:
+				invStmt.Account.BrokerID = res
+
+			case docBranchID:
+				bankStmt.Account.BranchCode = res
+
+			case docBankID:
+				bankStmt.Account.BankCode = res
+
+			case docTransDesc:
+				if trans != nil {
+					trans.Description = res
+				}
+
+			case docTransMemo:
+				if invTrans != nil {
+					invTrans.Memo = res
+				} else if trans != nil {
+					trans.Memo = res
+				}
+
+			case docTransID:
+				if trans != nil {
+					trans.ID = res
+				}
+
+			case docInvTransID:
+				invTrans.ID = res
+
+			case docPosSecID:
+				pos.SecurityID = res
+
+			case docInvSecID:
+				invTrans.SecurityID = res
+
+			case docTransDatePosted:
+				pd, perr := ParseDateTime(res)
+				if perr != nil {
+					return nil, perr
+				}
+				if invTrans != nil {
+					invTrans.PostedDate = pd
+				} else if trans != nil {
+					trans.PostedDate = pd
+				}
+
+			case docTransAmount:
+				if trans != nil {
+					if err := trans.Amount.ParseFromString(res); err != nil {
+						return nil, err
+					}
+
+					if trans.Amount.Value.Sign() == 1 {
+						trans.Type = CREDIT
+					} else {
+						trans.Type = DEBIT
+					}
+				}
+
+			case docPosUnits:
+				if err := pos.Units.ParseFromString(res); err != nil {
+					return nil, err
+				}
+
+			case docInvUnits:
+				if err := invTrans.Units.ParseFromString(res); err != nil {
+					return nil, err
+				}
+
+			case docInvUnitPrice:
+				if err := invTrans.UnitPrice.ParseFromString(res); err != nil {
+					return nil, err
+				}
+
+			case docPosMktVal:
+				if err := pos.MarketValue.ParseFromString(res); err != nil {
+					return nil, err
+				}
+
+			case docInvTotal:
+				if err := invTrans.Total.ParseFromString(res); err != nil {
+					return nil, err
+				}
+
+			case docBalAvailCash:
+				if invStmt.Balance == nil {
+					invStmt.Balance = &InvestmentBalance{}
+				}
+				if err := invStmt.Balance.AvailableCash.ParseFromString(res); err != nil {
+					return nil, err
+				}
+
+			case docBalMarginBalance:
+				if invStmt.Balance == nil {
+					invStmt.Balance = &InvestmentBalance{}
+				}
+				if err := invStmt.Balance.MarginBalance.ParseFromString(res); err != nil {
+					return nil, err
+				}
+			}
+
+			next = docNone
+
+		case xml.EndElement:
+			for len(stack) != 0 {
+				name := stack[len(stack)-1]
+				stack = stack[:len(stack)-1]
+
+				switch name {
+				case "STMTTRN":
+					switch {
+					case bankStmt != nil:
+						bankStmt.Transactions = append(bankStmt.Transactions, trans)
+					case ccStmt != nil:
+						ccStmt.Transactions = append(ccStmt.Transactions, trans)
+					}
+					trans = nil
+
+				case "BUYSTOCK", "SELLSTOCK", "INCOME":
+					invStmt.Transactions = append(invStmt.Transactions, invTrans)
+					invTrans = nil
+
+				case "POS", "POSSTOCK", "POSMF":
+					invStmt.Positions = append(invStmt.Positions, pos)
+					pos = nil
+
+				case "STMTRS":
+					doc.BankStatements = append(doc.BankStatements, bankStmt)
+					bankStmt = nil
+
+				case "CCSTMTRS":
+					doc.CreditCardStatements = append(doc.CreditCardStatements, ccStmt)
+					ccStmt = nil
+
+				case "INVSTMTRS":
+					doc.InvestmentStatements = append(doc.InvestmentStatements, invStmt)
+					invStmt = nil
+				}
+
+				if name == t.Name.Local {
+					break
+				}
+			}
+		}
+	}
+}
+
+func inStack(stack []string, name string) bool {
+	for i := len(stack) - 1; i >= 0; i-- {
+		if stack[i] == name {
+			return true
+		}
+		if stack[i] == "CCSTMTRS" || stack[i] == "INVSTMTRS" || stack[i] == "STMTRS" {
+			break
+		}
+	}
+	return false
+}
+
+func investmentTransactionType(tag string) InvestmentTransactionType {
+	switch tag {
+	case "BUYSTOCK":
+		return BUYSTOCK
+	case "SELLSTOCK":
+		return SELLSTOCK
+	case "INCOME":
+		return INCOME
+	}
+	return INVUNKNOWN
+}
@@ -0,0 +1,142 @@
+package ofx
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseDocumentCreditCard(t *testing.T) {
+	const doc = `<OFX><CREDITCARDMSGSRSV1><CCSTMTTRNRS><CCSTMTRS>
+<CCACCTFROM><ACCTID>4111-1111</ACCTID></CCACCTFROM>
+<BANKTRANLIST>
+<STMTTRN><TRNTYPE>DEBIT<DTPOSTED>20070329<TRNAMT>-75.00<FITID>CC1001<NAME>Groceries</STMTTRN>
+</BANKTRANLIST>
+</CCSTMTRS></CCSTMTTRNRS></CREDITCARDMSGSRSV1></OFX>`
+
+	d, err := ParseDocument(strings.NewReader(doc))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(d.BankStatements) != 0 {
+		t.Errorf("expected no bank statements, got %d", len(d.BankStatements))
+	}
+	if len(d.CreditCardStatements) != 1 {
+		t.Fatalf("expected 1 credit card statement, got %d", len(d.CreditCardStatements))
+	}
+
+	cc := d.CreditCardStatements[0]
+	if cc.Account.AccountNumber != "4111-1111" {
+		t.Errorf("wrong account number: %s", cc.Account.AccountNumber)
+	}
+	if len(cc.Transactions) != 1 {
+		t.Fatalf("expected 1 transaction, got %d", len(cc.Transactions))
+	}
+	if cc.Transactions[0].Description != "Groceries" || cc.Transactions[0].ID != "CC1001" {
+		t.Errorf("wrong transaction: %+v", cc.Transactions[0])
+	}
+}
+
+func TestParseDocumentInvestment(t *testing.T) {
+	const doc = `<OFX><INVSTMTMSGSRSV1><INVSTMTTRNRS><INVSTMTRS>
+<INVACCTFROM><BROKERID>brokerA<ACCTID>INV-1</INVACCTFROM>
+<INVTRANLIST>
+<BUYSTOCK><SECID><UNIQUEID>AAPL</UNIQUEID></SECID><DTPOSTED>20070329<UNITS>10<UNITPRICE>150.00<TOTAL>-1500.00<FITID>INV1001<MEMO>Buy Apple</BUYSTOCK>
+</INVTRANLIST>
+<INVPOSLIST>
+<POSSTOCK><SECID><UNIQUEID>AAPL</UNIQUEID></SECID><UNITS>10<MKTVAL>1600.00</POSSTOCK>
+</INVPOSLIST>
+<INVBAL><AVAILCASH>500.00<MARGINBALANCE>0.00</INVBAL>
+</INVSTMTRS></INVSTMTTRNRS></INVSTMTMSGSRSV1></OFX>`
+
+	d, err := ParseDocument(strings.NewReader(doc))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(d.InvestmentStatements) != 1 {
+		t.Fatalf("expected 1 investment statement, got %d", len(d.InvestmentStatements))
+	}
+	inv := d.InvestmentStatements[0]
+
+	if inv.Account.BrokerID != "brokerA" || inv.Account.AccountNumber != "INV-1" {
+		t.Errorf("wrong account: %+v", inv.Account)
+	}
+
+	if len(inv.Transactions) != 1 {
+		t.Fatalf("expected 1 transaction, got %d", len(inv.Transactions))
+	}
+	trans := inv.Transactions[0]
+	if trans.Type != BUYSTOCK || trans.SecurityID != "AAPL" || trans.ID != "INV1001" || trans.Memo != "Buy Apple" {
+		t.Errorf("wrong transaction: %+v", trans)
+	}
+	if trans.Units.Value.FloatString(2) != "10.00" || trans.UnitPrice.Value.FloatString(2) != "150.00" {
+		t.Errorf("wrong units/price: %+v", trans)
+	}
+
+	if len(inv.Positions) != 1 {
+		t.Fatalf("expected 1 position, got %d", len(inv.Positions))
+	}
+	pos := inv.Positions[0]
+	if pos.SecurityID != "AAPL" || pos.MarketValue.Value.FloatString(2) != "1600.00" {
+		t.Errorf("wrong position: %+v", pos)
+	}
+
+	if inv.Balance == nil || inv.Balance.AvailableCash.Value.FloatString(2) != "500.00" {
+		t.Errorf("wrong balance: %+v", inv.Balance)
+	}
+}
+
+func TestParseDocumentMultipleStatements(t *testing.T) {
+	const doc = `<OFX>
+<BANKMSGSRSV1><STMTTRNRS><STMTRS>
+<BANKACCTFROM><BANKID>987654321<BRANCHID>001<ACCTID>098-121</BANKACCTFROM>
+<BANKTRANLIST>
+<STMTTRN><TRNTYPE>DEBIT<DTPOSTED>20070329<TRNAMT>-50.00<FITID>B1<NAME>Coffee</STMTTRN>
+</BANKTRANLIST>
+</STMTRS></STMTTRNRS></BANKMSGSRSV1>
+<CREDITCARDMSGSRSV1><CCSTMTTRNRS><CCSTMTRS>
+<CCACCTFROM><ACCTID>4111-1111</ACCTID></CCACCTFROM>
+<BANKTRANLIST>
+<STMTTRN><TRNTYPE>DEBIT<DTPOSTED>20070330<TRNAMT>-75.00<FITID>C1<NAME>Groceries</STMTTRN>
+</BANKTRANLIST>
+</CCSTMTRS></CCSTMTTRNRS></CREDITCARDMSGSRSV1>
+</OFX>`
+
+	d, err := ParseDocument(strings.NewReader(doc))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(d.BankStatements) != 1 || len(d.BankStatements[0].Transactions) != 1 {
+		t.Fatalf("wrong bank statements: %+v", d.BankStatements)
+	}
+	if bank := d.BankStatements[0].Transactions[0]; bank.ID != "B1" || bank.Description != "Coffee" {
+		t.Errorf("bank transaction misattributed: %+v", bank)
+	}
+
+	if len(d.CreditCardStatements) != 1 || len(d.CreditCardStatements[0].Transactions) != 1 {
+		t.Fatalf("wrong credit card statements: %+v", d.CreditCardStatements)
+	}
+	if cc := d.CreditCardStatements[0].Transactions[0]; cc.ID != "C1" || cc.Description != "Groceries" {
+		t.Errorf("credit card transaction misattributed: %+v", cc)
+	}
+}
+
+func TestParseDocumentAmountOutsideTransaction(t *testing.T) {
+	const doc = `<OFX><BANKMSGSRSV1><STMTTRNRS><STMTRS>
+<BANKACCTFROM><BANKID>987654321<BRANCHID>001<ACCTID>098-121</BANKACCTFROM>
+<BANKTRANLIST>
+<TRNAMT>-50.00
+</BANKTRANLIST>
+</STMTRS></STMTTRNRS></BANKMSGSRSV1></OFX>`
+
+	d, err := ParseDocument(strings.NewReader(doc))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(d.BankStatements[0].Transactions) != 0 {
+		t.Errorf("expected the stray TRNAMT to be ignored, got %+v", d.BankStatements[0].Transactions)
+	}
+}
@@ -0,0 +1,291 @@
+package ofx
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+// formatDateTime renders t in the YYYYMMDDHHMMSS[offset:TZ] form accepted by
+// ParseDateTime. The offset segment is omitted for UTC times.
+func formatDateTime(t time.Time) string {
+	base := t.Format("20060102150405")
+
+	name, offset := t.Zone()
+	if offset == 0 {
+		return base
+	}
+
+	return fmt.Sprintf("%s[%g:%s]", base, float64(offset)/3600, name)
+}
+
+// textEscaper escapes the characters that would otherwise be misread as
+// markup when written into an OFX element body, mirroring what the XML/SGML
+// decoders unescape on the way back in.
+var textEscaper = strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;")
+
+// escapeText makes s safe to embed as element content, so that free-text
+// fields like transaction names and memos round-trip through Encode/Parse.
+func escapeText(s string) string {
+	return textEscaper.Replace(s)
+}
+
+// EncodeOptions controls how a Document or Ofx is rendered by Encoder.
+type EncodeOptions struct {
+	// Version is the OFX version to emit, e.g. "102" or "103" for OFX 1.x
+	// (SGML) and "200" or "211" for OFX 2.x (XML). Defaults to "102".
+	Version string
+
+	// Encoding is the header ENCODING value for OFX 1.x documents (e.g.
+	// "USASCII" or "UTF-8"). Ignored for OFX 2.x. Defaults to "USASCII".
+	Encoding string
+
+	// Charset is the header CHARSET value for OFX 1.x documents (e.g.
+	// "1252" or "NONE"). Ignored for OFX 2.x. Defaults to "1252".
+	Charset string
+}
+
+func (o EncodeOptions) version() string {
+	if o.Version == "" {
+		return "102"
+	}
+	return o.Version
+}
+
+func (o EncodeOptions) encoding() string {
+	if o.Encoding == "" {
+		return "USASCII"
+	}
+	return o.Encoding
+}
+
+func (o EncodeOptions) charset() string {
+	if o.Charset == "" {
+		return "1252"
+	}
+	return o.Charset
+}
+
+func (o EncodeOptions) isXML() bool {
+	return len(o.version()) > 0 && o.version()[0] == '2'
+}
+
+// Encoder writes OFX documents to an underlying io.Writer, producing OFX 1.x
+// (SGML) or OFX 2.x (XML) depending on EncodeOptions.Version.
+type Encoder struct {
+	w    io.Writer
+	opts EncodeOptions
+}
+
+// NewEncoder returns an Encoder that writes to w using opts.
+func NewEncoder(w io.Writer, opts EncodeOptions) *Encoder {
+	return &Encoder{w: w, opts: opts}
+}
+
+// Encode writes doc to the Encoder's underlying writer as a complete OFX
+// document, including the header block and the BANKMSGSRSV1,
+// CREDITCARDMSGSRSV1, and INVSTMTMSGSRSV1 envelopes for whichever statement
+// types doc contains.
+func (e *Encoder) Encode(doc *Document) error {
+	if err := e.writeHeader(); err != nil {
+		return err
+	}
+
+	if _, err := fmt.Fprint(e.w, "<OFX>"); err != nil {
+		return err
+	}
+
+	if len(doc.BankStatements) > 0 {
+		if err := e.writeBankMsgSet(doc.BankStatements); err != nil {
+			return err
+		}
+	}
+
+	if len(doc.CreditCardStatements) > 0 {
+		if err := e.writeCreditCardMsgSet(doc.CreditCardStatements); err != nil {
+			return err
+		}
+	}
+
+	if len(doc.InvestmentStatements) > 0 {
+		if err := e.writeInvestmentMsgSet(doc.InvestmentStatements); err != nil {
+			return err
+		}
+	}
+
+	_, err := fmt.Fprint(e.w, "</OFX>")
+	return err
+}
+
+func (e *Encoder) writeHeader() error {
+	if e.opts.isXML() {
+		_, err := fmt.Fprintf(e.w, "<?xml version=\"1.0\" encoding=\"UTF-8\"?>\n"+
+			"<?OFX OFXHEADER=\"200\" VERSION=\"%s\" SECURITY=\"NONE\" OLDFILEUID=\"NONE\" NEWFILEUID=\"NONE\"?>\n",
+			e.opts.version())
+		return err
+	}
+
+	_, err := fmt.Fprintf(e.w, "OFXHEADER:100\n"+
+		"DATA:OFXSGML\n"+
+		"VERSION:%s\n"+
+		"SECURITY:NONE\n"+
+		"ENCODING:%s\n"+
+		"CHARSET:%s\n"+
+		"COMPRESSION:NONE\n"+
+		"OLDFILEUID:NONE\n"+
+		"NEWFILEUID:NONE\n\n",
+		e.opts.version(), e.opts.encoding(), e.opts.charset())
+	return err
+}
+
+func (e *Encoder) writeBankMsgSet(stmts []*BankStatement) error {
+	if _, err := fmt.Fprint(e.w, "<BANKMSGSRSV1><STMTTRNRS>"); err != nil {
+		return err
+	}
+
+	for _, stmt := range stmts {
+		if _, err := fmt.Fprintf(e.w, "<STMTRS><BANKACCTFROM>"+
+			"<BANKID>%s</BANKID><BRANCHID>%s</BRANCHID><ACCTID>%s</ACCTID>"+
+			"</BANKACCTFROM><BANKTRANLIST>",
+			stmt.Account.BankCode, stmt.Account.BranchCode, stmt.Account.AccountNumber); err != nil {
+			return err
+		}
+
+		for _, t := range stmt.Transactions {
+			if err := e.writeTransaction(t); err != nil {
+				return err
+			}
+		}
+
+		if _, err := fmt.Fprint(e.w, "</BANKTRANLIST></STMTRS>"); err != nil {
+			return err
+		}
+	}
+
+	_, err := fmt.Fprint(e.w, "</STMTTRNRS></BANKMSGSRSV1>")
+	return err
+}
+
+func (e *Encoder) writeCreditCardMsgSet(stmts []*CreditCardStatement) error {
+	if _, err := fmt.Fprint(e.w, "<CREDITCARDMSGSRSV1><CCSTMTTRNRS>"); err != nil {
+		return err
+	}
+
+	for _, stmt := range stmts {
+		if _, err := fmt.Fprintf(e.w, "<CCSTMTRS><CCACCTFROM><ACCTID>%s</ACCTID></CCACCTFROM><BANKTRANLIST>",
+			stmt.Account.AccountNumber); err != nil {
+			return err
+		}
+
+		for _, t := range stmt.Transactions {
+			if err := e.writeTransaction(t); err != nil {
+				return err
+			}
+		}
+
+		if _, err := fmt.Fprint(e.w, "</BANKTRANLIST></CCSTMTRS>"); err != nil {
+			return err
+		}
+	}
+
+	_, err := fmt.Fprint(e.w, "</CCSTMTTRNRS></CREDITCARDMSGSRSV1>")
+	return err
+}
+
+func (e *Encoder) writeInvestmentMsgSet(stmts []*InvestmentStatement) error {
+	if _, err := fmt.Fprint(e.w, "<INVSTMTMSGSRSV1><INVSTMTTRNRS>"); err != nil {
+		return err
+	}
+
+	for _, stmt := range stmts {
+		if _, err := fmt.Fprintf(e.w, "<INVSTMTRS><INVACCTFROM><BROKERID>%s</BROKERID><ACCTID>%s</ACCTID></INVACCTFROM><INVTRANLIST>",
+			stmt.Account.BrokerID, stmt.Account.AccountNumber); err != nil {
+			return err
+		}
+
+		for _, t := range stmt.Transactions {
+			tag := investmentTransactionTag(t.Type)
+			if _, err := fmt.Fprintf(e.w, "<%s><SECID><UNIQUEID>%s</UNIQUEID></SECID>"+
+				"<DTPOSTED>%s</DTPOSTED><UNITS>%s</UNITS><UNITPRICE>%s</UNITPRICE>"+
+				"<TOTAL>%s</TOTAL><FITID>%s</FITID><MEMO>%s</MEMO></%s>",
+				tag, t.SecurityID, formatDateTime(t.PostedDate), t.Units.String(), t.UnitPrice.String(),
+				t.Total.String(), escapeText(t.ID), escapeText(t.Memo), tag); err != nil {
+				return err
+			}
+		}
+
+		if _, err := fmt.Fprint(e.w, "</INVTRANLIST>"); err != nil {
+			return err
+		}
+
+		if len(stmt.Positions) > 0 {
+			if _, err := fmt.Fprint(e.w, "<INVPOSLIST>"); err != nil {
+				return err
+			}
+			for _, p := range stmt.Positions {
+				if _, err := fmt.Fprintf(e.w, "<POS><SECID><UNIQUEID>%s</UNIQUEID></SECID>"+
+					"<UNITS>%s</UNITS><MKTVAL>%s</MKTVAL></POS>",
+					p.SecurityID, p.Units.String(), p.MarketValue.String()); err != nil {
+					return err
+				}
+			}
+			if _, err := fmt.Fprint(e.w, "</INVPOSLIST>"); err != nil {
+				return err
+			}
+		}
+
+		if stmt.Balance != nil {
+			if _, err := fmt.Fprintf(e.w, "<INVBAL><AVAILCASH>%s</AVAILCASH><MARGINBALANCE>%s</MARGINBALANCE></INVBAL>",
+				stmt.Balance.AvailableCash.String(), stmt.Balance.MarginBalance.String()); err != nil {
+				return err
+			}
+		}
+
+		if _, err := fmt.Fprint(e.w, "</INVSTMTRS>"); err != nil {
+			return err
+		}
+	}
+
+	_, err := fmt.Fprint(e.w, "</INVSTMTTRNRS></INVSTMTMSGSRSV1>")
+	return err
+}
+
+func (e *Encoder) writeTransaction(t *Transaction) error {
+	_, err := fmt.Fprintf(e.w, "<STMTTRN><TRNTYPE>%s</TRNTYPE><DTPOSTED>%s</DTPOSTED>"+
+		"<TRNAMT>%s</TRNAMT><FITID>%s</FITID><NAME>%s</NAME><MEMO>%s</MEMO></STMTTRN>",
+		t.Type, formatDateTime(t.PostedDate), t.Amount.String(), escapeText(t.ID), escapeText(t.Description), escapeText(t.Memo))
+	return err
+}
+
+func investmentTransactionTag(t InvestmentTransactionType) string {
+	switch t {
+	case BUYSTOCK:
+		return "BUYSTOCK"
+	case SELLSTOCK:
+		return "SELLSTOCK"
+	case INCOME:
+		return "INCOME"
+	}
+	return "INCOME"
+}
+
+// Encode writes o to w as a complete OFX document containing a single bank
+// statement response. See Encoder for the OFX 1.x/2.x selection rules.
+func (o *Ofx) Encode(w io.Writer, opts EncodeOptions) error {
+	doc := &Document{
+		BankStatements: []*BankStatement{
+			{
+				Account: BankAccount{
+					Type:          o.Type,
+					BankCode:      o.BankCode,
+					BranchCode:    o.BranchCode,
+					AccountNumber: o.AccountNumber,
+				},
+				Transactions: o.Transactions,
+			},
+		},
+	}
+
+	return NewEncoder(w, opts).Encode(doc)
+}
@@ -0,0 +1,67 @@
+package ofx
+
+import (
+	"bytes"
+	"math/big"
+	"testing"
+	"time"
+)
+
+func TestEncodeEscapesText(t *testing.T) {
+	o := &Ofx{
+		AccountNumber: "098-121",
+		BankCode:      "987654321",
+		Transactions: []*Transaction{
+			{
+				Type:        DEBIT,
+				Description: "Tom & Jerry <Films>",
+				Memo:        "AT&T bill",
+				PostedDate:  time.Date(2007, 3, 29, 13, 14, 15, 0, time.UTC),
+				ID:          "1001",
+				Amount:      Amount{Value: *big.NewRat(-5000, 100)},
+			},
+		},
+	}
+
+	for _, version := range []string{"102", "203"} {
+		var buf bytes.Buffer
+		if err := o.Encode(&buf, EncodeOptions{Version: version}); err != nil {
+			t.Fatalf("version %s: %v", version, err)
+		}
+
+		reparsed, err := Parse(bytes.NewReader(buf.Bytes()))
+		if err != nil {
+			t.Fatalf("version %s: reparse failed: %v\n%s", version, err, buf.String())
+		}
+
+		if len(reparsed.Transactions) != 1 {
+			t.Fatalf("version %s: expected 1 transaction, got %d", version, len(reparsed.Transactions))
+		}
+		trans := reparsed.Transactions[0]
+		if trans.Description != "Tom & Jerry <Films>" {
+			t.Errorf("version %s: Description = %q", version, trans.Description)
+		}
+		if trans.Memo != "AT&T bill" {
+			t.Errorf("version %s: Memo = %q", version, trans.Memo)
+		}
+	}
+}
+
+func TestFormatDateTimeFractionalOffset(t *testing.T) {
+	est := time.FixedZone("EST", int(-5.5*3600))
+	dt := time.Date(2007, 3, 29, 13, 14, 15, 0, est)
+
+	got := formatDateTime(dt)
+	want := "20070329131415[-5.5:EST]"
+	if got != want {
+		t.Errorf("formatDateTime(%v) = %q, want %q", dt, got, want)
+	}
+
+	reparsed, err := ParseDateTime(got)
+	if err != nil {
+		t.Fatalf("ParseDateTime(%q): %v", got, err)
+	}
+	if !reparsed.Equal(dt) {
+		t.Errorf("round-trip mismatch: got %v, want %v", reparsed, dt)
+	}
+}
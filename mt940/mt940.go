@@ -0,0 +1,349 @@
+// Package mt940 parses and emits SWIFT MT940 customer statement messages,
+// bridging them to the ofx package's Transaction and Ofx types so that banks
+// which only export MT940 can be handled the same way as OFX.
+package mt940
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mimizuno/ofx"
+)
+
+// Parser parses MT940 messages into *ofx.Ofx values.
+//
+// MT940's :61: field carries the entry date as MMDD only, with the year
+// implied by the surrounding statement. ReferenceDate anchors that
+// resolution; set it to the statement's closing date (or time.Now()) so
+// entry dates near a December/January boundary resolve to the correct year.
+type Parser struct {
+	ReferenceDate time.Time
+}
+
+// Parse reads a single MT940 message from r and returns it as an *ofx.Ofx.
+func (p *Parser) Parse(r io.Reader) (*ofx.Ofx, error) {
+	fields, err := scanFields(r)
+	if err != nil {
+		return nil, err
+	}
+
+	reference := p.ReferenceDate
+	if reference.IsZero() {
+		reference = time.Now()
+	}
+
+	result := &ofx.Ofx{Transactions: []*ofx.Transaction{}}
+	var trans *ofx.Transaction
+
+	for _, f := range fields {
+		switch f.tag {
+		case "25":
+			result.AccountNumber = strings.TrimSpace(f.value)
+
+		case "60F":
+			result.OpeningBalance, err = parseBalanceLine(f.value, reference)
+			if err != nil {
+				return nil, err
+			}
+
+		case "61":
+			trans, err = parseStatementLine(f.value, reference)
+			if err != nil {
+				return nil, err
+			}
+			result.Transactions = append(result.Transactions, trans)
+
+		case "86":
+			if trans != nil {
+				trans.Memo = strings.TrimSpace(strings.ReplaceAll(f.value, "\n", " "))
+			}
+
+		case "62F":
+			result.ClosingBalance, err = parseBalanceLine(f.value, reference)
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	return result, nil
+}
+
+type field struct {
+	tag   string
+	value string
+}
+
+// scanFields groups an MT940 message's lines by `:tag:` field, folding
+// continuation lines (those that don't open a new field) into the value of
+// the field they follow.
+func scanFields(r io.Reader) ([]field, error) {
+	var fields []field
+
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := scanner.Text()
+
+		if strings.HasPrefix(line, ":") {
+			end := strings.Index(line[1:], ":")
+			if end < 0 {
+				continue
+			}
+			fields = append(fields, field{tag: line[1 : end+1], value: line[end+2:]})
+			continue
+		}
+
+		if len(fields) == 0 {
+			continue
+		}
+		fields[len(fields)-1].value += "\n" + line
+	}
+
+	return fields, scanner.Err()
+}
+
+// parseBalanceLine parses a :60F:/:62F: value, e.g. "C070101EUR1000,00", into
+// an ofx.Balance. The three-character currency code must be present but is
+// neither checked nor retained, since ofx.Amount carries no currency of its
+// own.
+func parseBalanceLine(s string, reference time.Time) (*ofx.Balance, error) {
+	if len(s) < 10 {
+		return nil, fmt.Errorf("mt940: balance line too short: %q", s)
+	}
+
+	mark := s[0:1]
+	if mark != "C" && mark != "D" {
+		return nil, fmt.Errorf("mt940: missing debit/credit mark in %q", s)
+	}
+
+	year, err := strconv.Atoi(s[1:3])
+	if err != nil {
+		return nil, fmt.Errorf("mt940: invalid balance date in %q: %w", s, err)
+	}
+	month, err := strconv.Atoi(s[3:5])
+	if err != nil {
+		return nil, fmt.Errorf("mt940: invalid balance date in %q: %w", s, err)
+	}
+	day, err := strconv.Atoi(s[5:7])
+	if err != nil {
+		return nil, fmt.Errorf("mt940: invalid balance date in %q: %w", s, err)
+	}
+	asOf := time.Date(resolveCentury(reference, year), time.Month(month), day, 0, 0, 0, 0, time.UTC)
+
+	rest := s[7:]
+	if len(rest) < 3 {
+		return nil, fmt.Errorf("mt940: missing currency in %q", s)
+	}
+	amountStr := strings.Replace(rest[3:], ",", ".", 1)
+
+	bal := &ofx.Balance{AsOf: asOf}
+	if err := bal.Amount.ParseFromString(amountStr); err != nil {
+		return nil, err
+	}
+	if mark == "D" && bal.Amount.Value.Sign() > 0 {
+		bal.Amount.Value.Neg(&bal.Amount.Value)
+	}
+
+	return bal, nil
+}
+
+// parseStatementLine parses a :61: value, e.g. "0703290329D1234,56NTRFNONREF",
+// into a Transaction.
+func parseStatementLine(s string, reference time.Time) (*ofx.Transaction, error) {
+	if len(s) < 6 {
+		return nil, fmt.Errorf("mt940: statement line too short: %q", s)
+	}
+
+	valueYear, err := strconv.Atoi(s[0:2])
+	if err != nil {
+		return nil, fmt.Errorf("mt940: invalid value date in %q: %w", s, err)
+	}
+	valueMonth, err := strconv.Atoi(s[2:4])
+	if err != nil {
+		return nil, fmt.Errorf("mt940: invalid value date in %q: %w", s, err)
+	}
+	valueDay, err := strconv.Atoi(s[4:6])
+	if err != nil {
+		return nil, fmt.Errorf("mt940: invalid value date in %q: %w", s, err)
+	}
+	valueDate := time.Date(resolveCentury(reference, valueYear), time.Month(valueMonth), valueDay, 0, 0, 0, 0, time.UTC)
+
+	rest := s[6:]
+
+	var entryDate time.Time
+	if len(rest) >= 4 && isDigits(rest[0:4]) {
+		entryMonth, _ := strconv.Atoi(rest[0:2])
+		entryDay, _ := strconv.Atoi(rest[2:4])
+		entryYear := resolveYear(reference, entryMonth, entryDay)
+		entryDate = time.Date(entryYear, time.Month(entryMonth), entryDay, 0, 0, 0, 0, time.UTC)
+		rest = rest[4:]
+	}
+
+	var transType ofx.TransactionType
+	switch {
+	case strings.HasPrefix(rest, "RC"), strings.HasPrefix(rest, "RD"):
+		transType, rest = markType(rest[1:2]), rest[2:]
+	case strings.HasPrefix(rest, "C"), strings.HasPrefix(rest, "D"):
+		transType, rest = markType(rest[0:1]), rest[1:]
+	default:
+		return nil, fmt.Errorf("mt940: missing debit/credit mark in %q", s)
+	}
+
+	if len(rest) > 0 && !isDigit(rest[0]) {
+		rest = rest[1:] // optional funds code
+	}
+
+	amountEnd := 0
+	for amountEnd < len(rest) && (isDigit(rest[amountEnd]) || rest[amountEnd] == ',') {
+		amountEnd++
+	}
+	amountStr := strings.Replace(rest[:amountEnd], ",", ".", 1)
+	rest = rest[amountEnd:]
+
+	t := &ofx.Transaction{Type: transType, PostedDate: valueDate, UserDate: entryDate}
+	if err := t.Amount.ParseFromString(amountStr); err != nil {
+		return nil, err
+	}
+	if transType == ofx.DEBIT && t.Amount.Value.Sign() > 0 {
+		t.Amount.Value.Neg(&t.Amount.Value)
+	}
+
+	// The mandatory 1!a3!c transaction type code (e.g. "NTRF", "NMSC")
+	// always follows the amount; discard it before taking the reference.
+	if len(rest) < 4 {
+		return nil, fmt.Errorf("mt940: missing transaction type code in %q", s)
+	}
+	rest = rest[4:]
+
+	ref := rest
+	if idx := strings.Index(rest, "//"); idx >= 0 {
+		ref = rest[:idx]
+		t.ID = strings.TrimSpace(rest[idx+2:])
+	}
+	t.Description = strings.TrimSpace(ref)
+
+	return t, nil
+}
+
+func markType(mark string) ofx.TransactionType {
+	if mark == "D" {
+		return ofx.DEBIT
+	}
+	return ofx.CREDIT
+}
+
+func isDigit(b byte) bool { return b >= '0' && b <= '9' }
+
+func isDigits(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if !isDigit(s[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+// resolveCentury picks the century for a :61: value date's two-digit year
+// closest to reference.
+func resolveCentury(reference time.Time, yy int) int {
+	century := (reference.Year() / 100) * 100
+	year := century + yy
+	if year-reference.Year() > 50 {
+		year -= 100
+	} else if reference.Year()-year > 50 {
+		year += 100
+	}
+	return year
+}
+
+// resolveYear picks the year for a month/day pair (e.g. an MT940 entry date,
+// which carries no year of its own) closest to reference, so that statements
+// spanning a December/January boundary resolve to the correct year.
+func resolveYear(reference time.Time, month, day int) int {
+	best := reference.Year()
+	bestDiff := time.Duration(1<<63 - 1)
+
+	for _, y := range []int{reference.Year() - 1, reference.Year(), reference.Year() + 1} {
+		diff := time.Date(y, time.Month(month), day, 0, 0, 0, 0, time.UTC).Sub(reference)
+		if diff < 0 {
+			diff = -diff
+		}
+		if diff < bestDiff {
+			bestDiff = diff
+			best = y
+		}
+	}
+
+	return best
+}
+
+// Encode writes o as a single MT940 customer statement message to w.
+func Encode(w io.Writer, o *ofx.Ofx) error {
+	bw := bufio.NewWriter(w)
+
+	if _, err := fmt.Fprintf(bw, ":20:STATEMENT\n:25:%s\n:28C:1\n", o.AccountNumber); err != nil {
+		return err
+	}
+
+	if o.OpeningBalance != nil {
+		if err := writeBalanceLine(bw, "60F", o.OpeningBalance); err != nil {
+			return err
+		}
+	}
+
+	for _, t := range o.Transactions {
+		mark := "C"
+		if t.Type == ofx.DEBIT {
+			mark = "D"
+		}
+
+		amount := strings.TrimPrefix(t.Amount.String(), "-")
+		amount = strings.Replace(amount, ".", ",", 1)
+
+		ref := t.ID
+		if ref == "" {
+			ref = "NONREF"
+		}
+
+		if _, err := fmt.Fprintf(bw, ":61:%s%s%sNTRF%s\n", t.PostedDate.Format("060102"), mark, amount, ref); err != nil {
+			return err
+		}
+
+		memo := t.Memo
+		if memo == "" {
+			memo = t.Description
+		}
+		if memo != "" {
+			if _, err := fmt.Fprintf(bw, ":86:%s\n", memo); err != nil {
+				return err
+			}
+		}
+	}
+
+	if o.ClosingBalance != nil {
+		if err := writeBalanceLine(bw, "62F", o.ClosingBalance); err != nil {
+			return err
+		}
+	}
+
+	return bw.Flush()
+}
+
+// writeBalanceLine writes a :60F:/:62F: line for bal, e.g. "C070101EUR1000,00".
+// The currency is hardcoded to EUR, since ofx.Amount carries no currency of
+// its own for this to round-trip from.
+func writeBalanceLine(w io.Writer, tag string, bal *ofx.Balance) error {
+	mark := "C"
+	amount := strings.TrimPrefix(bal.Amount.String(), "-")
+	if bal.Amount.Value.Sign() < 0 {
+		mark = "D"
+	}
+	amount = strings.Replace(amount, ".", ",", 1)
+
+	_, err := fmt.Fprintf(w, ":%s:%s%sEUR%s\n", tag, mark, bal.AsOf.Format("060102"), amount)
+	return err
+}
@@ -0,0 +1,137 @@
+package mt940
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+)
+
+const sampleMessage = `:20:STATEMENT
+:25:098-121
+:28C:1
+:60F:C070101EUR1000,00
+:61:0703290329D50,00NTRFNONREF
+:86:Coffee shop
+:61:0703301230C200,00NTRFNONREF
+:86:Salary
+:62F:C070331EUR1150,00
+`
+
+func TestParse(t *testing.T) {
+	p := &Parser{ReferenceDate: time.Date(2007, 3, 31, 0, 0, 0, 0, time.UTC)}
+
+	o, err := p.Parse(strings.NewReader(sampleMessage))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if o.AccountNumber != "098-121" {
+		t.Errorf("wrong account number: %s", o.AccountNumber)
+	}
+
+	if len(o.Transactions) != 2 {
+		t.Fatalf("expected 2 transactions, got %d", len(o.Transactions))
+	}
+
+	first := o.Transactions[0]
+	if first.Amount.Value.FloatString(2) != "-50.00" {
+		t.Errorf("wrong amount for first transaction: %s", first.Amount.Value.FloatString(2))
+	}
+	if !first.PostedDate.Equal(time.Date(2007, 3, 29, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("wrong posted date: %s", first.PostedDate)
+	}
+	if first.Memo != "Coffee shop" {
+		t.Errorf("wrong memo: %s", first.Memo)
+	}
+	if first.Description != "NONREF" {
+		t.Errorf("wrong description: %s", first.Description)
+	}
+	if first.ID != "" {
+		t.Errorf("wrong ID: %s", first.ID)
+	}
+
+	second := o.Transactions[1]
+	if second.Amount.Value.FloatString(2) != "200.00" {
+		t.Errorf("wrong amount for second transaction: %s", second.Amount.Value.FloatString(2))
+	}
+
+	if o.OpeningBalance == nil || o.OpeningBalance.Amount.Value.FloatString(2) != "1000.00" {
+		t.Errorf("wrong opening balance: %+v", o.OpeningBalance)
+	}
+	if !o.OpeningBalance.AsOf.Equal(time.Date(2007, 1, 1, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("wrong opening balance date: %s", o.OpeningBalance.AsOf)
+	}
+	if o.ClosingBalance == nil || o.ClosingBalance.Amount.Value.FloatString(2) != "1150.00" {
+		t.Errorf("wrong closing balance: %+v", o.ClosingBalance)
+	}
+}
+
+func TestParseStatementLineReference(t *testing.T) {
+	p := &Parser{ReferenceDate: time.Date(2007, 3, 31, 0, 0, 0, 0, time.UTC)}
+
+	o, err := p.Parse(strings.NewReader(":25:1\n:61:0703290329D50,00NMSCABC123//BANKREF1\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	trans := o.Transactions[0]
+	if trans.Description != "ABC123" {
+		t.Errorf("wrong description: %s", trans.Description)
+	}
+	if trans.ID != "BANKREF1" {
+		t.Errorf("wrong ID: %s", trans.ID)
+	}
+}
+
+func TestParseYearRollover(t *testing.T) {
+	p := &Parser{ReferenceDate: time.Date(2008, 1, 2, 0, 0, 0, 0, time.UTC)}
+
+	o, err := p.Parse(strings.NewReader(":25:1\n:61:0712301230D10,00NTRFNONREF\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if o.Transactions[0].UserDate.Year() != 2007 {
+		t.Errorf("expected entry date to resolve to 2007, got %d", o.Transactions[0].UserDate.Year())
+	}
+}
+
+func TestEncode(t *testing.T) {
+	p := &Parser{ReferenceDate: time.Date(2007, 3, 31, 0, 0, 0, 0, time.UTC)}
+
+	o, err := p.Parse(strings.NewReader(sampleMessage))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+	if err := Encode(&buf, o); err != nil {
+		t.Fatal(err)
+	}
+
+	out := buf.String()
+	if !strings.Contains(out, ":25:098-121\n") {
+		t.Errorf("encoded message missing account: %s", out)
+	}
+	if !strings.Contains(out, ":61:070329D50,00NTRFNONREF\n") {
+		t.Errorf("encoded message missing first transaction: %s", out)
+	}
+	if !strings.Contains(out, ":60F:C070101EUR1000,00\n") {
+		t.Errorf("encoded message missing opening balance: %s", out)
+	}
+	if !strings.Contains(out, ":62F:C070331EUR1150,00\n") {
+		t.Errorf("encoded message missing closing balance: %s", out)
+	}
+
+	reparsed, err := p.Parse(strings.NewReader(out))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if reparsed.OpeningBalance.Amount.Value.FloatString(2) != "1000.00" {
+		t.Errorf("opening balance did not round-trip: %+v", reparsed.OpeningBalance)
+	}
+	if reparsed.ClosingBalance.Amount.Value.FloatString(2) != "1150.00" {
+		t.Errorf("closing balance did not round-trip: %+v", reparsed.ClosingBalance)
+	}
+}
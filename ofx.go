@@ -2,14 +2,9 @@ package ofx
 
 import (
 	"bytes"
-	"encoding/xml"
 	"fmt"
 	"io"
-	"log"
 	"math/big"
-	"regexp"
-	"strconv"
-	"strings"
 	"time"
 )
 
@@ -34,34 +29,35 @@ const (
 	CREDIT TransactionType = iota
 )
 
-type nextKey int
-
-const (
-	none            nextKey = iota
-	acctID          nextKey = iota
-	branchID        nextKey = iota
-	bankID          nextKey = iota
-	transAmount     nextKey = iota
-	transDatePosted nextKey = iota
-	transUserDate   nextKey = iota
-	transID         nextKey = iota
-	transDesc       nextKey = iota
-	transMemo       nextKey = iota
-)
+func (t TransactionType) String() string {
+	if t == CREDIT {
+		return "CREDIT"
+	}
+	return "DEBIT"
+}
 
 type Amount struct {
 	Value big.Rat
 }
 
+// ParseFromString parses s as DefaultAmountParser would, and stores the
+// result in a. Use AmountParser directly for locale-specific separators.
 func (a *Amount) ParseFromString(s string) error {
-	_, ok := a.Value.SetString(s)
-	if !ok {
-		return fmt.Errorf("Unable to parse string '%s' as an amount\n", s)
+	parsed, err := DefaultAmountParser.Parse(s)
+	if err != nil {
+		return err
 	}
 
+	a.Value = parsed.Value
 	return nil
 }
 
+// String renders the amount as a fixed-precision (2 decimal places) decimal
+// string, e.g. "-12.50", suitable for embedding in an OFX document.
+func (a Amount) String() string {
+	return a.Value.FloatString(2)
+}
+
 type Transaction struct {
 	Type        TransactionType
 	Description string
@@ -83,6 +79,13 @@ type Ofx struct {
 	BranchCode    string
 	AccountNumber string
 	Transactions  []*Transaction
+
+	// OpeningBalance and ClosingBalance are populated by formats that carry
+	// them explicitly, such as MT940's :60F: and :62F: fields. They are left
+	// nil when parsing an OFX document, which models only LEDGERBAL/AVAILBAL
+	// (see Balance) rather than a statement's opening/closing balance.
+	OpeningBalance *Balance
+	ClosingBalance *Balance
 }
 
 func (o Ofx) String() string {
@@ -99,160 +102,20 @@ func (o Ofx) String() string {
 
 // Parse parses an input stream and produces an Ofx instance summarizing it. In case of any errors
 // during the parse, a non-nil error is returned.
+//
+// Parse is a thin Handler built atop ParseStream. ParseStream reads the whole
+// input into memory before scanning it, and Parse keeps every transaction in
+// its result, so memory use grows with the size of the document. It keeps
+// only the first account it sees (the common case of a single bank statement
+// response), but collects every transaction streamed to it. Documents carrying credit card or
+// investment statements, or more than one bank statement, should use
+// ParseDocument instead.
 func Parse(f io.Reader) (*Ofx, error) {
-	ofx := &Ofx{Transactions: []*Transaction{}}
-	stack := make([]string, 1000)
-	stackPos := 0
-
-	next := none
-	var trans *Transaction = nil
-
-	dec := xml.NewDecoder(f)
-
-	tok, err := dec.RawToken()
-	for err == nil {
-		switch t := tok.(type) {
-		case xml.StartElement:
-			stack[stackPos] = t.Name.Local
-			stackPos++
-
-			switch t.Name.Local {
-			case "ACCTID":
-				next = acctID
-
-			case "BRANCHID":
-				next = branchID
-
-			case "BANKID":
-				next = bankID
-
-			case "STMTTRN":
-				trans = &Transaction{}
-
-			case "DTPOSTED":
-				next = transDatePosted
-
-			case "FITID":
-				next = transID
-
-			case "TRNAMT":
-				next = transAmount
-
-			case "NAME":
-				next = transDesc
-			case "MEMO":
-				next = transMemo
-			}
-
-		case xml.CharData:
-			var b bytes.Buffer
-			if _, err := b.Write(t); err != nil {
-				return nil, err
-			}
-			res := strings.TrimSpace(b.String())
-
-			switch next {
-			case acctID:
-				ofx.AccountNumber = res
-
-			case branchID:
-				ofx.BranchCode = res
-
-			case bankID:
-				ofx.BankCode = res
-
-			case transDesc:
-				trans.Description = res
-
-			case transMemo:
-				trans.Memo = res
-
-			case transID:
-				trans.ID = res
-
-			case transDatePosted:
-				var t time.Time
-				if t, err = parseDateTime(res); err != nil {
-					return nil, err
-				}
-				trans.PostedDate = t
-
-			case transAmount:
-				if err := trans.Amount.ParseFromString(res); err != nil {
-					return nil, err
-				}
-
-				if trans.Amount.Value.Sign() == 1 {
-					trans.Type = CREDIT
-				} else {
-					trans.Type = DEBIT
-				}
-			}
-
-			next = none
-
-		case xml.EndElement:
-			for stackPos != 0 {
-				if stack[stackPos-1] == "STMTTRN" {
-					ofx.Transactions = append(ofx.Transactions, trans)
-					trans = nil
-				}
-
-				if stack[stackPos-1] == t.Name.Local {
-					stackPos--
-					break
-				}
-				stackPos--
-			}
-
-		default:
-			log.Printf("Unknown: %T %s\n", t, t)
-		}
-
-		tok, err = dec.RawToken()
-
-		if err != nil && err != io.EOF {
-			log.Printf("Error: %s\n", err)
-		}
+	acc := &ofxAccumulator{ofx: &Ofx{Transactions: []*Transaction{}}}
+	if err := ParseStream(f, acc); err != nil {
+		return nil, err
 	}
 
-	return ofx, nil
-}
-
-func reFindStringSubmatchMap(re *regexp.Regexp, s string) map[string]string {
-	m := re.FindStringSubmatch(s)
-	result := make(map[string]string)
-	for i, name := range re.SubexpNames() {
-		if i != 0 && name != "" {
-			result[name] = m[i]
-		}
-	}
-	return result
+	return acc.ofx, nil
 }
 
-func parseDateTime(res string) (t time.Time, err error) {
-	re := regexp.MustCompile(`(?P<datetime>[.0-9]+)(?:\[(?P<offset>.+):(?P<name>.+)\])?`)
-	m := reFindStringSubmatchMap(re, res)
-
-	loc := time.UTC
-	if m["offset"] != "" {
-		offset, _ := strconv.ParseInt(m["offset"], 10, 32)
-		loc = time.FixedZone(m["name"], int(offset))
-	}
-
-	pattern := []string{
-		"20060102150405.999",
-		"20060102150405",
-		"20060102",
-	}
-
-	for _, pat := range pattern {
-		t, err = time.Parse(pat, m["datetime"])
-		t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), t.Second(), t.Nanosecond(), loc)
-		if err == nil {
-			return
-		}
-	}
-	err = fmt.Errorf("Invalid date posted string: '%s'", res)
-	return
-}
@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"io/ioutil"
 	"os"
+	"strings"
 	"testing"
 	"time"
 )
@@ -72,26 +73,105 @@ func BenchmarkOFXParse(b *testing.B) {
 }
 
 func TestParseDateTime(t *testing.T) {
-	pst := time.FixedZone("PST", -7)
+	pst := time.FixedZone("PST", -8*3600)
+	jst := time.FixedZone("JST", 9*3600)
+	estHalf := time.FixedZone("EST", int(-5.5*3600))
 	cases := []struct {
 		format   string
 		expected time.Time
 	}{
+		{format: "200703", expected: time.Date(2007, 3, 1, 0, 0, 0, 0, time.UTC)},
 		{format: "20070329", expected: time.Date(2007, 3, 29, 0, 0, 0, 0, time.UTC)},
+		{format: "2007032913", expected: time.Date(2007, 3, 29, 13, 0, 0, 0, time.UTC)},
+		{format: "200703291314", expected: time.Date(2007, 3, 29, 13, 14, 0, 0, time.UTC)},
 		{format: "20070329131415", expected: time.Date(2007, 3, 29, 13, 14, 15, 0, time.UTC)},
 		{format: "20070329131415.123", expected: time.Date(2007, 3, 29, 13, 14, 15, 123*1000*1000, time.UTC)},
 		{format: "20070329[-8:PST]", expected: time.Date(2007, 3, 29, 0, 0, 0, 0, pst)},
 		{format: "20070329131415[-8:PST]", expected: time.Date(2007, 3, 29, 13, 14, 15, 0, pst)},
 		{format: "20070329131415.123[-8:PST]", expected: time.Date(2007, 3, 29, 13, 14, 15, 123*1000*1000, pst)},
+		{format: "20070329131415[+9:JST]", expected: time.Date(2007, 3, 29, 13, 14, 15, 0, jst)},
+		{format: "20070329131415[-05.5:EST]", expected: time.Date(2007, 3, 29, 13, 14, 15, 0, estHalf)},
 	}
 
 	for _, c := range cases {
-		actual, err := parseDateTime(c.format)
+		actual, err := ParseDateTime(c.format)
 		if err != nil {
 			t.Errorf("Error occured: %v by %v", err, c.format)
 		}
-		if actual.Format(time.RFC3339) != c.expected.Format(time.RFC3339) {
-			t.Errorf("expected: %v, actual: %v, diff:%v", c.expected, actual, c.expected.Sub(actual))
+		if !actual.Equal(c.expected) {
+			t.Errorf("%s: expected: %v, actual: %v, diff:%v", c.format, c.expected, actual, c.expected.Sub(actual))
 		}
 	}
 }
+
+func TestParseAmount(t *testing.T) {
+	cases := []struct {
+		amount   string
+		parser   AmountParser
+		expected string
+	}{
+		{amount: "-50.00", parser: DefaultAmountParser, expected: "-50.00"},
+		{amount: "+1,234.56", parser: DefaultAmountParser, expected: "1234.56"},
+		{amount: "$1,234.56", parser: DefaultAmountParser, expected: "1234.56"},
+		{amount: "1.234,56", parser: AmountParser{DecimalSeparator: ',', GroupSeparator: '.'}, expected: "1234.56"},
+		{amount: "R$ 1.234,56", parser: AmountParser{DecimalSeparator: ',', GroupSeparator: '.'}, expected: "1234.56"},
+	}
+
+	for _, c := range cases {
+		a, err := c.parser.Parse(c.amount)
+		if err != nil {
+			t.Errorf("%s: unexpected error: %v", c.amount, err)
+			continue
+		}
+		if a.Value.FloatString(2) != c.expected {
+			t.Errorf("%s: expected %s, got %s", c.amount, c.expected, a.Value.FloatString(2))
+		}
+	}
+}
+
+type recordingHandler struct {
+	accounts     []*Account
+	transactions []*Transaction
+	balances     []*Balance
+}
+
+func (h *recordingHandler) OnAccount(a *Account)         { h.accounts = append(h.accounts, a) }
+func (h *recordingHandler) OnTransaction(t *Transaction) { h.transactions = append(h.transactions, t) }
+func (h *recordingHandler) OnBalance(b *Balance)         { h.balances = append(h.balances, b) }
+
+const streamSample = `<OFX><BANKMSGSRSV1><STMTTRNRS><STMTRS>
+<BANKACCTFROM><BANKID>987654321<BRANCHID>001<ACCTID>098-121</BANKACCTFROM>
+<BANKTRANLIST>
+<STMTTRN><TRNTYPE>DEBIT<DTPOSTED>20070329<TRNAMT>-50.00<FITID>1001<NAME>Coffee</STMTTRN>
+</BANKTRANLIST>
+<LEDGERBAL><BALAMT>1234.56<DTASOF>20070331</LEDGERBAL>
+</STMTRS></STMTTRNRS></BANKMSGSRSV1></OFX>`
+
+func TestParseStream(t *testing.T) {
+	h := &recordingHandler{}
+	if err := ParseStream(strings.NewReader(streamSample), h); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(h.accounts) != 1 || h.accounts[0].AccountNumber != "098-121" {
+		t.Errorf("unexpected accounts: %+v", h.accounts)
+	}
+	if len(h.transactions) != 1 || h.transactions[0].Amount.Value.FloatString(2) != "-50.00" {
+		t.Errorf("unexpected transactions: %+v", h.transactions)
+	}
+	if len(h.balances) != 1 || h.balances[0].Amount.Value.FloatString(2) != "1234.56" {
+		t.Errorf("unexpected balances: %+v", h.balances)
+	}
+}
+
+func TestParseViaStream(t *testing.T) {
+	o, err := Parse(strings.NewReader(streamSample))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	verifyOfx(t, o, "098-121", "987654321")
+	if len(o.Transactions) != 1 {
+		t.Fatalf("expected 1 transaction, got %d", len(o.Transactions))
+	}
+}
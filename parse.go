@@ -0,0 +1,124 @@
+package ofx
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// dateTimeRe matches an OFX date/time value: a run of digits (optionally
+// with a fractional-seconds suffix), followed by an optional timezone
+// offset in brackets, e.g. "20070329131415.123[-8:PST]" or "20070329[+9:JST]".
+// Compiled once at package init rather than per call.
+var dateTimeRe = regexp.MustCompile(`(?P<datetime>[.0-9]+)(?:\[(?P<offset>[-+]?[0-9]+(?:\.[0-9]+)?):(?P<name>.+)\])?`)
+
+// dateTimeLayouts are tried in order, from most to least specific, so that
+// partial timestamps (a bare year-month, or anything missing seconds) still
+// parse instead of being rejected outright.
+var dateTimeLayouts = []string{
+	"20060102150405.999",
+	"20060102150405",
+	"200601021504",
+	"2006010215",
+	"20060102",
+	"200601",
+}
+
+func reFindStringSubmatchMap(re *regexp.Regexp, s string) map[string]string {
+	m := re.FindStringSubmatch(s)
+	result := make(map[string]string)
+	for i, name := range re.SubexpNames() {
+		if i != 0 && name != "" {
+			result[name] = m[i]
+		}
+	}
+	return result
+}
+
+// ParseDateTime parses an OFX date/time value such as "20070329131415.123"
+// or "20070329[-8:PST]" into a time.Time. Partial timestamps (YYYYMM through
+// YYYYMMDDHHMM) are accepted in addition to the full YYYYMMDDHHMMSS form, and
+// the offset may carry a fractional number of hours, e.g. "[-05.5:EST]" or
+// "[+9:JST]".
+func ParseDateTime(s string) (t time.Time, err error) {
+	m := reFindStringSubmatchMap(dateTimeRe, s)
+
+	loc := time.UTC
+	if m["offset"] != "" {
+		offsetHours, perr := strconv.ParseFloat(m["offset"], 64)
+		if perr != nil {
+			return time.Time{}, fmt.Errorf("Invalid timezone offset in date string: '%s'", s)
+		}
+		loc = time.FixedZone(m["name"], int(offsetHours*3600))
+	}
+
+	for _, layout := range dateTimeLayouts {
+		t, err = time.Parse(layout, m["datetime"])
+		if err == nil {
+			t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), t.Second(), t.Nanosecond(), loc)
+			return t, nil
+		}
+	}
+
+	err = fmt.Errorf("Invalid date posted string: '%s'", s)
+	return
+}
+
+// AmountParser parses OFX amount strings using configurable decimal and
+// grouping separators, so that documents from locales that use comma
+// decimals (e.g. "1.234,56") parse the same as US-style ones (e.g.
+// "1,234.56"). Currency symbols and other stray punctuation are stripped.
+type AmountParser struct {
+	// DecimalSeparator is the character that separates the integer and
+	// fractional parts of an amount. Defaults to '.'.
+	DecimalSeparator byte
+
+	// GroupSeparator is the character used to group digits (e.g. thousands).
+	// It is discarded during parsing. Defaults to ','.
+	GroupSeparator byte
+}
+
+// DefaultAmountParser parses amounts using the US convention: '.' as the
+// decimal separator and ',' as the grouping separator.
+var DefaultAmountParser = AmountParser{DecimalSeparator: '.', GroupSeparator: ','}
+
+// ParseAmount parses s using DefaultAmountParser.
+func ParseAmount(s string) (Amount, error) {
+	return DefaultAmountParser.Parse(s)
+}
+
+// Parse parses s into an Amount, stripping currency symbols and the
+// configured grouping separator and normalizing the configured decimal
+// separator to '.' before handing off to big.Rat.
+func (p AmountParser) Parse(s string) (Amount, error) {
+	dec := p.DecimalSeparator
+	if dec == 0 {
+		dec = '.'
+	}
+	grp := p.GroupSeparator
+	if grp == 0 {
+		grp = ','
+	}
+
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; {
+		case c == dec:
+			b.WriteByte('.')
+		case c == grp:
+			// Grouping separator; discarded.
+		case c >= '0' && c <= '9', c == '+', c == '-':
+			b.WriteByte(c)
+		}
+		// Anything else (currency symbols, spaces, ...) is discarded too.
+	}
+
+	var a Amount
+	if _, ok := a.Value.SetString(b.String()); !ok {
+		return Amount{}, fmt.Errorf("Unable to parse string '%s' as an amount\n", s)
+	}
+
+	return a, nil
+}
@@ -0,0 +1,292 @@
+package ofx
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+)
+
+// tokenDecoder is the minimal surface decodeDocument needs from either
+// encoding/xml.Decoder (OFX 2.x) or sgmlDecoder (OFX 1.x).
+type tokenDecoder interface {
+	RawToken() (xml.Token, error)
+}
+
+// Header carries the OFX header block that precedes the document body: the
+// key:value lines above an OFX 1.x SGML body, or the attributes of the
+// `<?OFX ...?>` processing instruction above an OFX 2.x XML body.
+type Header struct {
+	OFXHeader   string
+	Data        string
+	Version     string
+	Security    string
+	Encoding    string
+	Charset     string
+	Compression string
+	OldFileUID  string
+	NewFileUID  string
+}
+
+func (h *Header) isXML() bool {
+	return h.OFXHeader == "200" || strings.HasPrefix(h.Version, "2")
+}
+
+// parseHeader splits data into its Header and the remaining document body,
+// choosing the OFX 1.x SGML header format or the OFX 2.x XML processing
+// instruction based on which one the body opens with.
+func parseHeader(data []byte) (*Header, []byte) {
+	if looksLikeXML(data) {
+		return parseXMLHeader(data)
+	}
+	return parseSGMLHeader(data)
+}
+
+func looksLikeXML(data []byte) bool {
+	trimmed := bytes.TrimLeft(data, " \t\r\n")
+	return bytes.HasPrefix(trimmed, []byte("<?xml")) || bytes.HasPrefix(trimmed, []byte("<?OFX"))
+}
+
+// parseSGMLHeader reads the OFX 1.x `KEY:VALUE` header lines up to the first
+// blank line or the first line that opens a tag, and returns the rest of
+// data, unconsumed, as the SGML body.
+func parseSGMLHeader(data []byte) (*Header, []byte) {
+	h := &Header{}
+	pos := 0
+
+	for pos < len(data) {
+		nl := bytes.IndexByte(data[pos:], '\n')
+		lineEnd := len(data)
+		next := len(data)
+		if nl >= 0 {
+			lineEnd = pos + nl
+			next = lineEnd + 1
+		}
+
+		line := bytes.TrimSpace(data[pos:lineEnd])
+
+		if len(line) == 0 {
+			pos = next
+			break
+		}
+		if bytes.HasPrefix(line, []byte("<")) {
+			break
+		}
+
+		if idx := bytes.IndexByte(line, ':'); idx >= 0 {
+			key := string(bytes.TrimSpace(line[:idx]))
+			val := string(bytes.TrimSpace(line[idx+1:]))
+			h.set(key, val)
+		}
+
+		pos = next
+	}
+
+	return h, data[pos:]
+}
+
+var xmlPIAttr = regexp.MustCompile(`(\w+)="([^"]*)"`)
+
+// parseXMLHeader reads the `<?xml ...?>` and `<?OFX ...?>` processing
+// instructions of an OFX 2.x document and returns the rest of data,
+// unconsumed, as the XML body.
+func parseXMLHeader(data []byte) (*Header, []byte) {
+	h := &Header{OFXHeader: "200"}
+	body := data
+
+	start := bytes.Index(data, []byte("<?OFX"))
+	if start < 0 {
+		return h, body
+	}
+
+	end := bytes.Index(data[start:], []byte("?>"))
+	if end < 0 {
+		return h, body
+	}
+
+	pi := string(data[start+len("<?OFX") : start+end])
+	for _, m := range xmlPIAttr.FindAllStringSubmatch(pi, -1) {
+		h.set(m[1], m[2])
+	}
+
+	return h, data[start+end+len("?>"):]
+}
+
+func (h *Header) set(key, val string) {
+	switch key {
+	case "OFXHEADER":
+		h.OFXHeader = val
+	case "DATA":
+		h.Data = val
+	case "VERSION":
+		h.Version = val
+	case "SECURITY":
+		h.Security = val
+	case "ENCODING":
+		h.Encoding = val
+	case "CHARSET":
+		h.Charset = val
+	case "COMPRESSION":
+		h.Compression = val
+	case "OLDFILEUID":
+		h.OldFileUID = val
+	case "NEWFILEUID":
+		h.NewFileUID = val
+	}
+}
+
+// sgmlFrame tracks one open tag on the sgmlDecoder's stack, and whether it
+// has already received character data (making it a "leaf" that must be
+// auto-closed as soon as a sibling tag starts).
+type sgmlFrame struct {
+	name     string
+	hasValue bool
+}
+
+// sgmlDecoder tokenizes OFX 1.x SGML, where leaf elements such as
+// `<DTPOSTED>20070329` are never explicitly closed: their value runs up to
+// the next `<`, and the element is implicitly closed by the next sibling or
+// parent closing tag. It produces the same xml.Token stream encoding/xml
+// would for an equivalent well-formed document, so decodeDocument does not
+// need to know which front end produced it.
+type sgmlDecoder struct {
+	data  []byte
+	pos   int
+	stack []sgmlFrame
+	queue []xml.Token
+}
+
+func newSGMLDecoder(data []byte) *sgmlDecoder {
+	return &sgmlDecoder{data: data}
+}
+
+// RawToken returns the next token in the document, auto-closing dangling
+// leaf elements as needed. It returns io.EOF once the body is exhausted.
+func (d *sgmlDecoder) RawToken() (xml.Token, error) {
+	if len(d.queue) == 0 {
+		if err := d.fill(); err != nil {
+			return nil, err
+		}
+	}
+
+	tok := d.queue[0]
+	d.queue = d.queue[1:]
+	return tok, nil
+}
+
+// fill scans the next tag (and any character data following it) and appends
+// the tokens it implies to d.queue.
+func (d *sgmlDecoder) fill() error {
+	d.skipSpace()
+	if d.pos >= len(d.data) {
+		return io.EOF
+	}
+
+	if d.data[d.pos] != '<' {
+		idx := bytes.IndexByte(d.data[d.pos:], '<')
+		if idx < 0 {
+			d.pos = len(d.data)
+			return io.EOF
+		}
+		d.pos += idx
+	}
+
+	gt := bytes.IndexByte(d.data[d.pos:], '>')
+	if gt < 0 {
+		return fmt.Errorf("ofx: unterminated tag at offset %d", d.pos)
+	}
+	tag := string(d.data[d.pos+1 : d.pos+gt])
+	d.pos += gt + 1
+
+	if strings.HasPrefix(tag, "/") {
+		if len(d.stack) == 0 {
+			return fmt.Errorf("ofx: unmatched closing tag <%s>", tag)
+		}
+		d.closeTo(tag[1:])
+		return nil
+	}
+
+	if strings.HasPrefix(tag, "?") || strings.HasPrefix(tag, "!") {
+		return d.fill()
+	}
+
+	name := strings.TrimSuffix(tag, "/")
+	selfClosed := name != tag
+
+	d.autoCloseLeaves()
+
+	d.stack = append(d.stack, sgmlFrame{name: name})
+	d.queue = append(d.queue, xml.StartElement{Name: xml.Name{Local: name}})
+
+	if selfClosed {
+		d.stack = d.stack[:len(d.stack)-1]
+		d.queue = append(d.queue, xml.EndElement{Name: xml.Name{Local: name}})
+		return nil
+	}
+
+	idx := bytes.IndexByte(d.data[d.pos:], '<')
+	var text []byte
+	if idx < 0 {
+		text = d.data[d.pos:]
+		d.pos = len(d.data)
+	} else {
+		text = d.data[d.pos : d.pos+idx]
+		d.pos += idx
+	}
+
+	if trimmed := bytes.TrimSpace(text); len(trimmed) > 0 {
+		d.queue = append(d.queue, xml.CharData(unescapeSGMLText(trimmed)))
+		d.stack[len(d.stack)-1].hasValue = true
+	}
+
+	return nil
+}
+
+// autoCloseLeaves closes any tag on top of the stack that already received
+// character data, since in SGML its next sibling starting is what marks its
+// end.
+func (d *sgmlDecoder) autoCloseLeaves() {
+	for len(d.stack) > 0 && d.stack[len(d.stack)-1].hasValue {
+		top := d.stack[len(d.stack)-1]
+		d.stack = d.stack[:len(d.stack)-1]
+		d.queue = append(d.queue, xml.EndElement{Name: xml.Name{Local: top.name}})
+	}
+}
+
+// closeTo handles a real closing tag, auto-closing any dangling leaves down
+// to (and including) the matching open tag.
+func (d *sgmlDecoder) closeTo(name string) {
+	for len(d.stack) > 0 {
+		top := d.stack[len(d.stack)-1]
+		d.stack = d.stack[:len(d.stack)-1]
+		d.queue = append(d.queue, xml.EndElement{Name: xml.Name{Local: top.name}})
+		if top.name == name {
+			return
+		}
+	}
+}
+
+// sgmlTextUnescaper undoes the escaping Encoder applies to element text, so
+// that values round-trip through Encode/Parse unchanged in OFX 1.x SGML the
+// same way encoding/xml already does for OFX 2.x.
+var sgmlTextUnescaper = strings.NewReplacer("&lt;", "<", "&gt;", ">", "&amp;", "&")
+
+func unescapeSGMLText(b []byte) []byte {
+	if !bytes.ContainsRune(b, '&') {
+		return b
+	}
+	return []byte(sgmlTextUnescaper.Replace(string(b)))
+}
+
+func (d *sgmlDecoder) skipSpace() {
+	for d.pos < len(d.data) {
+		switch d.data[d.pos] {
+		case ' ', '\t', '\r', '\n':
+			d.pos++
+		default:
+			return
+		}
+	}
+}
@@ -0,0 +1,279 @@
+package ofx
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+// Handler receives callbacks from ParseStream as it scans a document, rather
+// than waiting for the whole document to be parsed into memory.
+type Handler interface {
+	// OnAccount is called once a bank or credit card account's identity
+	// (BANKACCTFROM or CCACCTFROM) has been fully read.
+	OnAccount(*Account)
+
+	// OnTransaction is called for each transaction as soon as its closing
+	// </STMTTRN> tag is seen.
+	OnTransaction(*Transaction)
+
+	// OnBalance is called for each LEDGERBAL or AVAILBAL block as soon as
+	// it closes.
+	OnBalance(*Balance)
+}
+
+// Account identifies the bank or credit card account a statement belongs
+// to. BankCode and BranchCode are left zero for credit card accounts, which
+// carry only an account number.
+type Account struct {
+	Type          AccountType
+	BankCode      string
+	BranchCode    string
+	AccountNumber string
+}
+
+// Balance is a LEDGERBAL or AVAILBAL block.
+type Balance struct {
+	Amount Amount
+	AsOf   time.Time
+}
+
+type streamNextKey int
+
+const (
+	streamNone streamNextKey = iota
+	streamAcctID
+	streamBranchID
+	streamBankID
+	streamTransAmount
+	streamTransDatePosted
+	streamTransID
+	streamTransDesc
+	streamTransMemo
+	streamBalAmount
+	streamBalDate
+)
+
+// ParseStream scans an OFX document and invokes h as each account,
+// transaction, and balance is read, without building a result for the whole
+// document. The input itself is read into memory in full before scanning
+// begins. The internal tag stack grows on demand, and a malformed tag is
+// returned as an error rather than logged and skipped.
+//
+// ParseStream only reports bank and credit card statements (STMTRS,
+// CCSTMTRS); use ParseDocument for investment statements.
+func ParseStream(r io.Reader, h Handler) error {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return err
+	}
+
+	header, body := parseHeader(data)
+
+	var dec tokenDecoder
+	if header.isXML() {
+		dec = xml.NewDecoder(bytes.NewReader(body))
+	} else {
+		dec = newSGMLDecoder(body)
+	}
+
+	return streamTokens(dec, h)
+}
+
+func streamTokens(dec tokenDecoder, h Handler) error {
+	var stack []string
+
+	next := streamNone
+	var acct *Account = nil
+	var trans *Transaction = nil
+	var bal *Balance = nil
+
+	for {
+		tok, err := dec.RawToken()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("ofx: malformed document: %w", err)
+		}
+
+		switch t := tok.(type) {
+		case xml.StartElement:
+			stack = append(stack, t.Name.Local)
+
+			switch t.Name.Local {
+			case "BANKACCTFROM", "CCACCTFROM":
+				acct = &Account{}
+
+			case "STMTTRN":
+				trans = &Transaction{}
+
+			case "LEDGERBAL", "AVAILBAL":
+				bal = &Balance{}
+
+			case "ACCTID":
+				next = streamAcctID
+
+			case "BRANCHID":
+				next = streamBranchID
+
+			case "BANKID":
+				next = streamBankID
+
+			case "DTPOSTED":
+				next = streamTransDatePosted
+
+			case "FITID":
+				next = streamTransID
+
+			case "TRNAMT":
+				next = streamTransAmount
+
+			case "NAME":
+				next = streamTransDesc
+
+			case "MEMO":
+				next = streamTransMemo
+
+			case "BALAMT":
+				next = streamBalAmount
+
+			case "DTASOF":
+				next = streamBalDate
+			}
+
+		case xml.CharData:
+			res := strings.TrimSpace(string(t))
+
+			switch next {
+			case streamAcctID:
+				if acct != nil {
+					acct.AccountNumber = res
+				}
+
+			case streamBranchID:
+				if acct != nil {
+					acct.BranchCode = res
+				}
+
+			case streamBankID:
+				if acct != nil {
+					acct.BankCode = res
+				}
+
+			case streamTransDesc:
+				if trans == nil {
+					return fmt.Errorf("ofx: malformed document: NAME outside STMTTRN")
+				}
+				trans.Description = res
+
+			case streamTransMemo:
+				if trans == nil {
+					return fmt.Errorf("ofx: malformed document: MEMO outside STMTTRN")
+				}
+				trans.Memo = res
+
+			case streamTransID:
+				if trans == nil {
+					return fmt.Errorf("ofx: malformed document: FITID outside STMTTRN")
+				}
+				trans.ID = res
+
+			case streamTransDatePosted:
+				if trans == nil {
+					return fmt.Errorf("ofx: malformed document: DTPOSTED outside STMTTRN")
+				}
+				pd, perr := ParseDateTime(res)
+				if perr != nil {
+					return perr
+				}
+				trans.PostedDate = pd
+
+			case streamTransAmount:
+				if trans == nil {
+					return fmt.Errorf("ofx: malformed document: TRNAMT outside STMTTRN")
+				}
+				if err := trans.Amount.ParseFromString(res); err != nil {
+					return err
+				}
+				if trans.Amount.Value.Sign() == 1 {
+					trans.Type = CREDIT
+				} else {
+					trans.Type = DEBIT
+				}
+
+			case streamBalAmount:
+				if bal == nil {
+					return fmt.Errorf("ofx: malformed document: BALAMT outside LEDGERBAL/AVAILBAL")
+				}
+				if err := bal.Amount.ParseFromString(res); err != nil {
+					return err
+				}
+
+			case streamBalDate:
+				if bal == nil {
+					return fmt.Errorf("ofx: malformed document: DTASOF outside LEDGERBAL/AVAILBAL")
+				}
+				ad, aerr := ParseDateTime(res)
+				if aerr != nil {
+					return aerr
+				}
+				bal.AsOf = ad
+			}
+
+			next = streamNone
+
+		case xml.EndElement:
+			for len(stack) != 0 {
+				name := stack[len(stack)-1]
+				stack = stack[:len(stack)-1]
+
+				switch name {
+				case "BANKACCTFROM", "CCACCTFROM":
+					h.OnAccount(acct)
+					acct = nil
+
+				case "STMTTRN":
+					h.OnTransaction(trans)
+					trans = nil
+
+				case "LEDGERBAL", "AVAILBAL":
+					h.OnBalance(bal)
+					bal = nil
+				}
+
+				if name == t.Name.Local {
+					break
+				}
+			}
+		}
+	}
+}
+
+// ofxAccumulator is the Handler Parse uses to rebuild its flat *Ofx result
+// on top of the streaming parser.
+type ofxAccumulator struct {
+	ofx        *Ofx
+	gotAccount bool
+}
+
+func (a *ofxAccumulator) OnAccount(acct *Account) {
+	if a.gotAccount {
+		return
+	}
+	a.gotAccount = true
+
+	a.ofx.Type = acct.Type
+	a.ofx.BankCode = acct.BankCode
+	a.ofx.BranchCode = acct.BranchCode
+	a.ofx.AccountNumber = acct.AccountNumber
+}
+
+func (a *ofxAccumulator) OnTransaction(t *Transaction) {
+	a.ofx.Transactions = append(a.ofx.Transactions, t)
+}
+
+func (a *ofxAccumulator) OnBalance(b *Balance) {}